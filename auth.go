@@ -0,0 +1,29 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// basicAuthMiddleware wraps a handler with HTTP Basic Auth, used to gate
+// the OPDS routes. If user and pass are both empty (no OPDS_USER /
+// OPDS_PASSWORD env vars configured), auth is skipped entirely so the
+// feed keeps working for local/dev use.
+func basicAuthMiddleware(user, pass string, next http.HandlerFunc) http.HandlerFunc {
+	if user == "" && pass == "" {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		gotUser, gotPass, ok := r.BasicAuth()
+		userMatch := subtle.ConstantTimeCompare([]byte(gotUser), []byte(user)) == 1
+		passMatch := subtle.ConstantTimeCompare([]byte(gotPass), []byte(pass)) == 1
+		if !ok || !userMatch || !passMatch {
+			w.Header().Set("WWW-Authenticate", `Basic realm="OPDS"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
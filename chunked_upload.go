@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// defaultChunkSize is the chunk size advertised to clients by
+// /upload/init. 4MB comfortably clears ParseMultipartForm's 32MB ceiling
+// while keeping each chunk request small.
+const defaultChunkSize = 4 << 20 // 4 MB
+
+// ChunkUploadManager tracks in-progress resumable uploads on disk, keyed by
+// upload ID, so large files (e.g. docx documents) can be sent in pieces
+// instead of hitting the 32MB ParseMultipartForm limit in one request.
+type ChunkUploadManager struct {
+	baseDir string
+}
+
+// NewChunkUploadManager creates a manager that stores chunks under
+// os.TempDir()/pdfconv-uploads.
+func NewChunkUploadManager() (*ChunkUploadManager, error) {
+	baseDir := filepath.Join(os.TempDir(), "pdfconv-uploads")
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create chunk upload dir: %w", err)
+	}
+	return &ChunkUploadManager{baseDir: baseDir}, nil
+}
+
+// Init starts a new resumable upload and returns its ID and the chunk size
+// the client should split the file into.
+func (m *ChunkUploadManager) Init() (uploadID string, chunkSize int64, err error) {
+	uploadID, err = generateUploadID()
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := os.MkdirAll(m.uploadDir(uploadID), 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create upload dir: %w", err)
+	}
+
+	return uploadID, defaultChunkSize, nil
+}
+
+// SaveChunk writes one chunk of an in-progress upload to disk.
+func (m *ChunkUploadManager) SaveChunk(uploadID string, index int, data []byte) error {
+	if !isValidUploadID(uploadID) {
+		return fmt.Errorf("invalid upload_id: %s", uploadID)
+	}
+
+	dir := m.uploadDir(uploadID)
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("unknown upload_id: %s", uploadID)
+	}
+
+	chunkPath := filepath.Join(dir, fmt.Sprintf("%010d.chunk", index))
+	if err := os.WriteFile(chunkPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write chunk %d: %w", index, err)
+	}
+
+	return nil
+}
+
+// Complete concatenates every chunk for uploadID in order, validates the
+// result against totalHash (skipped if empty), and removes the chunk
+// directory. The caller is responsible for running ConvertToPDF on the
+// returned bytes.
+func (m *ChunkUploadManager) Complete(uploadID string, totalHash string) ([]byte, error) {
+	if !isValidUploadID(uploadID) {
+		return nil, fmt.Errorf("invalid upload_id: %s", uploadID)
+	}
+
+	dir := m.uploadDir(uploadID)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unknown upload_id: %s", uploadID)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names) // zero-padded chunk index names sort numerically
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		chunk, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chunk %s: %w", name, err)
+		}
+		buf.Write(chunk)
+	}
+
+	data := buf.Bytes()
+
+	if totalHash != "" {
+		if actual := sha256Hex(data); actual != totalHash {
+			return nil, fmt.Errorf("hash mismatch: expected %s, got %s", totalHash, actual)
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		log.Printf("failed to clean up chunk dir for upload %s: %v", uploadID, err)
+	}
+
+	return data, nil
+}
+
+func (m *ChunkUploadManager) uploadDir(uploadID string) string {
+	return filepath.Join(m.baseDir, uploadID)
+}
+
+// uploadIDPattern matches exactly what generateUploadID produces: 16 random
+// bytes, hex-encoded. Callers must validate any client-supplied upload_id
+// against this before it touches the filesystem, since it's otherwise
+// joined directly into a path.
+var uploadIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+func isValidUploadID(uploadID string) bool {
+	return uploadIDPattern.MatchString(uploadID)
+}
+
+func generateUploadID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate upload ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -1,13 +1,11 @@
 package main
 
 import (
-	"archive/zip"
 	"bytes"
 	"fmt"
 	"image"
 	_ "image/jpeg"
 	_ "image/png"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,22 +14,6 @@ import (
 	"github.com/jung-kurt/gofpdf"
 )
 
-// ConvertToPDF converts various file types to PDF
-func ConvertToPDF(filename string, data []byte) ([]byte, error) {
-	ext := strings.ToLower(filepath.Ext(filename))
-
-	switch ext {
-	case ".jpg", ".jpeg", ".png":
-		return convertImageToPDF(filename, data)
-	case ".txt":
-		return convertTextToPDF(filename, data)
-	case ".docx":
-		return convertDocxToPDF(filename, data)
-	default:
-		return nil, fmt.Errorf("unsupported file type: %s", ext)
-	}
-}
-
 // convertImageToPDF converts image files to PDF
 func convertImageToPDF(filename string, data []byte) ([]byte, error) {
 	// Decode the image to get dimensions
@@ -142,30 +124,6 @@ func convertTextToPDF(filename string, data []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// CreateZip creates a ZIP archive containing multiple PDF files
-func CreateZip(files map[string][]byte) ([]byte, error) {
-	var buf bytes.Buffer
-	zipWriter := zip.NewWriter(&buf)
-
-	for filename, data := range files {
-		fileWriter, err := zipWriter.Create(filename)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create file in zip: %w", err)
-		}
-
-		_, err = io.Copy(fileWriter, bytes.NewReader(data))
-		if err != nil {
-			return nil, fmt.Errorf("failed to write file to zip: %w", err)
-		}
-	}
-
-	if err := zipWriter.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close zip: %w", err)
-	}
-
-	return buf.Bytes(), nil
-}
-
 func convertDocxToPDF(filename string, data []byte) ([]byte, error) {
 	// Create a temporary file for the input
 	tmpDir := os.TempDir()
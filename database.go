@@ -2,6 +2,7 @@ package main
 
 import (
 	"database/sql"
+	"io"
 	"log"
 	"time"
 
@@ -17,6 +18,30 @@ type FileRecord struct {
 	OriginalName string
 	PDFData      []byte
 	UploadedAt   time.Time
+	SourceHash   string
+	PDFHash      string
+}
+
+// JobState is the lifecycle state of a conversion job.
+type JobState string
+
+const (
+	JobPending JobState = "pending"
+	JobRunning JobState = "running"
+	JobDone    JobState = "done"
+	JobFailed  JobState = "failed"
+)
+
+type Job struct {
+	ID           int64
+	OriginalName string
+	State        JobState
+	RetryCount   int
+	WorkerID     sql.NullInt64
+	ErrorMessage sql.NullString
+	FileID       sql.NullInt64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
 }
 
 type Database struct {
@@ -44,7 +69,9 @@ func NewDatabase(filepath string) (*Database, error) {
 		id SERIAL PRIMARY KEY,
 		original_name TEXT NOT NULL,
 		pdf_data BYTEA NOT NULL,
-		uploaded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		uploaded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		source_hash TEXT,
+		pdf_hash TEXT
 	);`
 
 	_, err = db.Exec(createTableSQL)
@@ -52,15 +79,63 @@ func NewDatabase(filepath string) (*Database, error) {
 		return nil, err
 	}
 
+	// CREATE TABLE IF NOT EXISTS above is a no-op against a files table
+	// from before source_hash/pdf_hash existed, so add them explicitly
+	// here too.
+	alterTableSQL := `ALTER TABLE files
+		ADD COLUMN IF NOT EXISTS source_hash TEXT,
+		ADD COLUMN IF NOT EXISTS pdf_hash TEXT;`
+
+	_, err = db.Exec(alterTableSQL)
+	if err != nil {
+		return nil, err
+	}
+
+	// A NULL source_hash (e.g. merged/OCR batches we don't content-address)
+	// never conflicts with another NULL under a unique index, so only
+	// genuine duplicate single-file uploads collide.
+	createSourceHashIndexSQL := `CREATE UNIQUE INDEX IF NOT EXISTS idx_files_source_hash ON files (source_hash);`
+
+	_, err = db.Exec(createSourceHashIndexSQL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Jobs track the lifecycle of an async conversion submitted via
+	// HandleUpload: pending -> running -> done|failed.
+	createJobsTableSQL := `CREATE TABLE IF NOT EXISTS jobs (
+		id SERIAL PRIMARY KEY,
+		original_name TEXT NOT NULL,
+		state TEXT NOT NULL DEFAULT 'pending',
+		retry_count INTEGER NOT NULL DEFAULT 0,
+		worker_id INTEGER,
+		error_message TEXT,
+		file_id INTEGER REFERENCES files(id),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	_, err = db.Exec(createJobsTableSQL)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Database{db: db}, nil
 }
 
-func (d *Database) SaveFile(originalName string, pdfData []byte) (int64, error) {
+// SaveFile stores a converted PDF. sourceHash and pdfHash are the SHA-256
+// digests of the uploaded source and the resulting PDF; pass empty strings
+// when the caller doesn't content-address the result (e.g. merged/OCR
+// batches). If a file with the same sourceHash already exists, its row is
+// returned unchanged instead of inserting a duplicate.
+func (d *Database) SaveFile(originalName string, pdfData []byte, sourceHash, pdfHash string) (int64, error) {
 	var id int64
-	// Postgres uses $1, $2 placeholders and RETURNING to get the id
 	err := d.db.QueryRow(
-		"INSERT INTO files (original_name, pdf_data) VALUES ($1, $2) RETURNING id",
-		originalName, pdfData,
+		`INSERT INTO files (original_name, pdf_data, source_hash, pdf_hash)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (source_hash) DO UPDATE SET original_name = files.original_name
+		 RETURNING id`,
+		originalName, pdfData, nullableHash(sourceHash), nullableHash(pdfHash),
 	).Scan(&id)
 
 	if err != nil {
@@ -69,13 +144,86 @@ func (d *Database) SaveFile(originalName string, pdfData []byte) (int64, error)
 	return id, nil
 }
 
+// GetFileBySourceHash looks up an already-converted file by the SHA-256 of
+// its source bytes, letting HandleUpload short-circuit re-conversion of a
+// file that's already been uploaded.
+func (d *Database) GetFileBySourceHash(sourceHash string) (*FileRecord, error) {
+	var record FileRecord
+	var srcHash, pdfHash sql.NullString
+
+	err := d.db.QueryRow(
+		"SELECT id, original_name, pdf_data, uploaded_at, source_hash, pdf_hash FROM files WHERE source_hash = $1",
+		sourceHash,
+	).Scan(&record.ID, &record.OriginalName, &record.PDFData, &record.UploadedAt, &srcHash, &pdfHash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	record.SourceHash = srcHash.String
+	record.PDFHash = pdfHash.String
+	return &record, nil
+}
+
+func nullableHash(hash string) interface{} {
+	if hash == "" {
+		return nil
+	}
+	return hash
+}
+
 func (d *Database) GetFile(id int64) (*FileRecord, error) {
 	var record FileRecord
+	var srcHash, pdfHash sql.NullString
+
 	// Postgres ises $1 placeholder
 	err := d.db.QueryRow(
-		"SELECT id, original_name, pdf_data, uploaded_at FROM files WHERE id = $1",
+		"SELECT id, original_name, pdf_data, uploaded_at, source_hash, pdf_hash FROM files WHERE id = $1",
+		id,
+	).Scan(&record.ID, &record.OriginalName, &record.PDFData, &record.UploadedAt, &srcHash, &pdfHash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	record.SourceHash = srcHash.String
+	record.PDFHash = pdfHash.String
+	return &record, nil
+}
+
+// GetFileName fetches just a file's original name, letting callers build
+// download headers without pulling the full PDF blob into memory.
+func (d *Database) GetFileName(id int64) (string, error) {
+	var originalName string
+	err := d.db.QueryRow("SELECT original_name FROM files WHERE id = $1", id).Scan(&originalName)
+	if err != nil {
+		return "", err
+	}
+	return originalName, nil
+}
+
+// StreamFile writes a single file's PDF data directly to w without loading
+// any other file into memory, so a batch download only ever holds one
+// file's bytes at a time.
+func (d *Database) StreamFile(id int64, w io.Writer) error {
+	var pdfData []byte
+	err := d.db.QueryRow("SELECT pdf_data FROM files WHERE id = $1", id).Scan(&pdfData)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(pdfData)
+	return err
+}
+
+// GetFileMeta fetches a file's metadata (name, upload time) without its
+// PDF blob, used by the OPDS feed to describe an entry.
+func (d *Database) GetFileMeta(id int64) (*FileRecord, error) {
+	var record FileRecord
+	err := d.db.QueryRow(
+		"SELECT id, original_name, uploaded_at FROM files WHERE id = $1",
 		id,
-	).Scan(&record.ID, &record.OriginalName, &record.PDFData, &record.UploadedAt)
+	).Scan(&record.ID, &record.OriginalName, &record.UploadedAt)
 
 	if err != nil {
 		return nil, err
@@ -83,6 +231,30 @@ func (d *Database) GetFile(id int64) (*FileRecord, error) {
 	return &record, nil
 }
 
+// ListFiles returns file metadata ordered by most recently uploaded, for
+// paginated catalog views such as the OPDS feed.
+func (d *Database) ListFiles(limit, offset int) ([]*FileRecord, error) {
+	rows, err := d.db.Query(
+		"SELECT id, original_name, uploaded_at FROM files ORDER BY uploaded_at DESC LIMIT $1 OFFSET $2",
+		limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*FileRecord, 0, limit)
+	for rows.Next() {
+		var record FileRecord
+		if err := rows.Scan(&record.ID, &record.OriginalName, &record.UploadedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+
+	return records, rows.Err()
+}
+
 func (d *Database) GetFiles(ids []int64) ([]*FileRecord, error) {
 	records := make([]*FileRecord, 0, len(ids))
 
@@ -98,6 +270,87 @@ func (d *Database) GetFiles(ids []int64) ([]*FileRecord, error) {
 	return records, nil
 }
 
+// CreateJob inserts a new pending job for originalName and returns its ID.
+func (d *Database) CreateJob(originalName string) (int64, error) {
+	var id int64
+	err := d.db.QueryRow(
+		"INSERT INTO jobs (original_name, state) VALUES ($1, $2) RETURNING id",
+		originalName, JobPending,
+	).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// MarkJobRunning transitions a job to running and records which worker
+// picked it up.
+func (d *Database) MarkJobRunning(id int64, workerID int) error {
+	_, err := d.db.Exec(
+		"UPDATE jobs SET state = $1, worker_id = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3",
+		JobRunning, workerID, id,
+	)
+	return err
+}
+
+// MarkJobDone transitions a job to done and links it to the saved file.
+func (d *Database) MarkJobDone(id int64, fileID int64) error {
+	_, err := d.db.Exec(
+		"UPDATE jobs SET state = $1, file_id = $2, error_message = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $3",
+		JobDone, fileID, id,
+	)
+	return err
+}
+
+// MarkJobFailed transitions a job to failed and records the error and
+// retryCount, which JobQueue passes as the number of failed attempts.
+func (d *Database) MarkJobFailed(id int64, retryCount int, errMsg string) error {
+	_, err := d.db.Exec(
+		"UPDATE jobs SET state = $1, retry_count = $2, error_message = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4",
+		JobFailed, retryCount, errMsg, id,
+	)
+	return err
+}
+
+// IncrementJobRetry bumps the retry count for a job that is about to be
+// retried but keeps it pending so a worker picks it up again.
+func (d *Database) IncrementJobRetry(id int64, retryCount int, errMsg string) error {
+	_, err := d.db.Exec(
+		"UPDATE jobs SET state = $1, retry_count = $2, error_message = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4",
+		JobPending, retryCount, errMsg, id,
+	)
+	return err
+}
+
+func (d *Database) GetJob(id int64) (*Job, error) {
+	var job Job
+	err := d.db.QueryRow(
+		"SELECT id, original_name, state, retry_count, worker_id, error_message, file_id, created_at, updated_at FROM jobs WHERE id = $1",
+		id,
+	).Scan(&job.ID, &job.OriginalName, &job.State, &job.RetryCount, &job.WorkerID, &job.ErrorMessage, &job.FileID, &job.CreatedAt, &job.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (d *Database) GetJobs(ids []int64) ([]*Job, error) {
+	jobs := make([]*Job, 0, len(ids))
+
+	for _, id := range ids {
+		job, err := d.GetJob(id)
+		if err != nil {
+			log.Printf("Error fetching job ID %d: %v", id, err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
 func (d *Database) Close() error {
 	return d.db.Close()
 }
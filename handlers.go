@@ -1,6 +1,9 @@
 package main
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,21 +15,53 @@ import (
 )
 
 type Handler struct {
-	db *Database
+	db      *Database
+	queue   *JobQueue
+	uploads *ChunkUploadManager
 }
 
-func NewHandler(db *Database) *Handler {
-	return &Handler{db: db}
+func NewHandler(db *Database, queue *JobQueue, uploads *ChunkUploadManager) *Handler {
+	return &Handler{db: db, queue: queue, uploads: uploads}
 }
 
 type UploadResponse struct {
 	Success bool    `json:"success"`
 	Message string  `json:"message"`
-	FileIDs []int64 `json:"file_ids,omitempty"`
-	FileID  int64   `json:"file_id,omitempty"`
+	JobIDs  []int64 `json:"job_ids,omitempty"`
+	JobID   int64   `json:"job_id,omitempty"`
 }
 
-// HandleUpload handles both single and multiple file uploads
+// JobResponse reports the current state of a conversion job so the
+// frontend can poll /jobs/{id} while the conversion runs in the background.
+type JobResponse struct {
+	ID           int64  `json:"id"`
+	OriginalName string `json:"original_name"`
+	State        string `json:"state"`
+	RetryCount   int    `json:"retry_count"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	FileID       int64  `json:"file_id,omitempty"`
+}
+
+func toJobResponse(job *Job) JobResponse {
+	resp := JobResponse{
+		ID:           job.ID,
+		OriginalName: job.OriginalName,
+		State:        string(job.State),
+		RetryCount:   job.RetryCount,
+	}
+	if job.ErrorMessage.Valid {
+		resp.ErrorMessage = job.ErrorMessage.String
+	}
+	if job.FileID.Valid {
+		resp.FileID = job.FileID.Int64
+	}
+	return resp
+}
+
+// HandleUpload enqueues the uploaded files as conversion jobs (one job per
+// file, or one job for a batch of images being merged or OCR'd) and returns
+// immediately; clients poll GET /jobs/{id} (or /jobs?ids=) for completion
+// since conversions such as LibreOffice docx runs can be slow.
 func (h *Handler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Received request: %s %s", r.Method, r.URL.Path)
 	if r.Method != http.MethodPost {
@@ -53,17 +88,16 @@ func (h *Handler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var fileIDs []int64
-
+	// Read every uploaded file up front so both the merge path and the
+	// per-file path below work from plain bytes.
+	var blobs []NamedBlob
 	for _, fileHeader := range files {
-		// Open uploaded file
 		file, err := fileHeader.Open()
 		if err != nil {
 			log.Printf("Error opening file %s: %v", fileHeader.Filename, err)
 			continue
 		}
 
-		// Read file data
 		data, err := io.ReadAll(file)
 		file.Close()
 		if err != nil {
@@ -71,50 +105,277 @@ func (h *Handler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		// Convert to PDF
-		pdfData, err := ConvertToPDF(fileHeader.Filename, data)
+		blobs = append(blobs, NamedBlob{Filename: fileHeader.Filename, Data: data})
+	}
+
+	var jobIDs []int64
+	merge := r.FormValue("merge") == "true"
+	ocr := r.FormValue("ocr") == "true"
+
+	var imageBlobs, otherBlobs []NamedBlob
+	for _, b := range blobs {
+		if (merge || ocr) && isImageFilename(b.Filename) {
+			imageBlobs = append(imageBlobs, b)
+		} else {
+			otherBlobs = append(otherBlobs, b)
+		}
+	}
+
+	switch {
+	case ocr && len(imageBlobs) > 0:
+		// A single OCR'd image keeps its own filename, same as a plain
+		// image conversion; only batches of several images get merged into
+		// one PDF, which needs a name of its own.
+		ocrOpts := OCROptions{Lang: r.FormValue("lang")}
+
+		resultName := "ocr.pdf"
+		if len(imageBlobs) == 1 {
+			resultName = imageBlobs[0].Filename
+		}
+
+		jobID, err := h.db.CreateJob(resultName)
 		if err != nil {
-			log.Printf("Error converting file %s: %v", fileHeader.Filename, err)
-			respondJSON(w, http.StatusBadRequest, UploadResponse{
-				Success: false,
-				Message: fmt.Sprintf("Failed to convert %s: %v", fileHeader.Filename, err),
-			})
-			return
+			log.Printf("Error creating OCR job: %v", err)
+		} else {
+			h.queue.EnqueueOCR(jobID, resultName, imageBlobs, ocrOpts)
+			jobIDs = append(jobIDs, jobID)
+		}
+	case merge && len(imageBlobs) > 1:
+		mergeOpts := MergeOptions{
+			Layout:   MergeLayout(r.FormValue("layout")),
+			PageSize: PageSize(r.FormValue("page_size")),
 		}
 
-		// Save to database
-		fileID, err := h.db.SaveFile(fileHeader.Filename, pdfData)
+		jobID, err := h.db.CreateJob("merged.pdf")
 		if err != nil {
-			log.Printf("Error saving file %s: %v", fileHeader.Filename, err)
-			continue
+			log.Printf("Error creating merge job: %v", err)
+		} else {
+			h.queue.EnqueueMerge(jobID, "merged.pdf", imageBlobs, mergeOpts)
+			jobIDs = append(jobIDs, jobID)
 		}
+	default:
+		// Not enough images to merge/OCR as a batch (or neither was
+		// requested); convert them individually like any other file.
+		otherBlobs = append(otherBlobs, imageBlobs...)
+	}
 
-		fileIDs = append(fileIDs, fileID)
+	for _, b := range otherBlobs {
+		jobID, err := h.queueSingleFile(b.Filename, b.Data)
+		if err != nil {
+			log.Printf("Error queuing %s: %v", b.Filename, err)
+			continue
+		}
+		jobIDs = append(jobIDs, jobID)
 	}
 
-	if len(fileIDs) == 0 {
+	if len(jobIDs) == 0 {
 		respondJSON(w, http.StatusInternalServerError, UploadResponse{
 			Success: false,
-			Message: "Failed to process any files",
+			Message: "Failed to queue any files",
 		})
 		return
 	}
 
-	// If single file, return single file ID
-	if len(fileIDs) == 1 {
-		respondJSON(w, http.StatusOK, UploadResponse{
+	// If a single job was queued, return its job ID
+	if len(jobIDs) == 1 {
+		respondJSON(w, http.StatusAccepted, UploadResponse{
 			Success: true,
-			Message: "File uploaded and converted successfully",
-			FileID:  fileIDs[0],
+			Message: "File queued for conversion",
+			JobID:   jobIDs[0],
 		})
 		return
 	}
 
 	// Multiple files
-	respondJSON(w, http.StatusOK, UploadResponse{
+	respondJSON(w, http.StatusAccepted, UploadResponse{
 		Success: true,
-		Message: fmt.Sprintf("%d files uploaded and converted successfully", len(fileIDs)),
-		FileIDs: fileIDs,
+		Message: fmt.Sprintf("%d files queued for conversion", len(jobIDs)),
+		JobIDs:  jobIDs,
+	})
+}
+
+// HandleJobStatus handles GET /jobs/{id}, returning the status of a single
+// conversion job.
+func (h *Handler) HandleJobStatus(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.db.GetJob(id)
+	if err != nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, toJobResponse(job))
+}
+
+// HandleJobsStatus handles GET /jobs?ids=1,2,3, returning the status of
+// multiple conversion jobs.
+func (h *Handler) HandleJobsStatus(w http.ResponseWriter, r *http.Request) {
+	idsStr := r.URL.Query().Get("ids")
+	if idsStr == "" {
+		http.Error(w, "Job IDs required", http.StatusBadRequest)
+		return
+	}
+
+	idStrs := strings.Split(idsStr, ",")
+	var ids []int64
+
+	for _, idStr := range idStrs {
+		id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		http.Error(w, "No valid job IDs", http.StatusBadRequest)
+		return
+	}
+
+	jobs, err := h.db.GetJobs(ids)
+	if err != nil {
+		http.Error(w, "Error retrieving jobs", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]JobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		responses = append(responses, toJobResponse(job))
+	}
+
+	respondJSON(w, http.StatusOK, responses)
+}
+
+// queueSingleFile creates a job for a single source file and enqueues it
+// for conversion, shared by HandleUpload and HandleUploadComplete. filename
+// is stripped down to its base name so a client-supplied path (e.g. from
+// /upload/complete's filename query param) can't escape the conversion
+// strategies' temp directories, the same way ocr.go's tesseract path does.
+func (h *Handler) queueSingleFile(filename string, data []byte) (int64, error) {
+	filename = filepath.Base(filename)
+
+	jobID, err := h.db.CreateJob(filename)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	h.queue.Enqueue(jobID, filename, data)
+	return jobID, nil
+}
+
+type UploadInitResponse struct {
+	UploadID  string `json:"upload_id"`
+	ChunkSize int64  `json:"chunk_size"`
+}
+
+// HandleUploadInit handles POST /upload/init, starting a resumable chunked
+// upload and returning its ID and the chunk size the client should use.
+func (h *Handler) HandleUploadInit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	uploadID, chunkSize, err := h.uploads.Init()
+	if err != nil {
+		log.Printf("Error initializing chunked upload: %v", err)
+		http.Error(w, "Failed to initialize upload", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, UploadInitResponse{UploadID: uploadID, ChunkSize: chunkSize})
+}
+
+// HandleUploadChunk handles POST /upload/chunk?upload_id=...&index=N,
+// storing one chunk of an in-progress resumable upload.
+func (h *Handler) HandleUploadChunk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	uploadID := r.URL.Query().Get("upload_id")
+	if uploadID == "" {
+		http.Error(w, "upload_id required", http.StatusBadRequest)
+		return
+	}
+
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil {
+		http.Error(w, "Invalid chunk index", http.StatusBadRequest)
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read chunk body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.uploads.SaveChunk(uploadID, index, data); err != nil {
+		log.Printf("Error saving chunk %d for upload %s: %v", index, uploadID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, UploadResponse{Success: true, Message: "Chunk stored"})
+}
+
+// HandleUploadComplete handles POST
+// /upload/complete?upload_id=...&filename=...&total_hash=..., assembling
+// the stored chunks, validating the total hash, and queuing the result for
+// conversion like a normal upload.
+func (h *Handler) HandleUploadComplete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	uploadID := r.URL.Query().Get("upload_id")
+	if uploadID == "" {
+		http.Error(w, "upload_id required", http.StatusBadRequest)
+		return
+	}
+
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		http.Error(w, "filename required", http.StatusBadRequest)
+		return
+	}
+
+	totalHash := r.URL.Query().Get("total_hash")
+	if totalHash == "" {
+		http.Error(w, "total_hash required", http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.uploads.Complete(uploadID, totalHash)
+	if err != nil {
+		log.Printf("Error completing upload %s: %v", uploadID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jobID, err := h.queueSingleFile(filename, data)
+	if err != nil {
+		log.Printf("Error queuing completed upload %s: %v", uploadID, err)
+		respondJSON(w, http.StatusInternalServerError, UploadResponse{
+			Success: false,
+			Message: "Failed to queue file for conversion",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, UploadResponse{
+		Success: true,
+		Message: "File assembled and queued for conversion",
+		JobID:   jobID,
 	})
 }
 
@@ -180,42 +441,130 @@ func (h *Handler) HandleDownloadZip(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get files from database
-	records, err := h.db.GetFiles(ids)
-	if err != nil {
-		http.Error(w, "Error retrieving files", http.StatusInternalServerError)
-		return
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "zip"
 	}
 
-	if len(records) == 0 {
-		http.Error(w, "No files found", http.StatusNotFound)
-		return
+	switch format {
+	case "zip":
+		h.streamZip(w, ids)
+	case "tar.gz":
+		h.streamTarGz(w, ids)
+	default:
+		http.Error(w, "Unsupported format, use zip or tar.gz", http.StatusBadRequest)
 	}
+}
 
-	// Prepare files map for ZIP
-	files := make(map[string][]byte)
-	for _, record := range records {
-		pdfFilename := strings.TrimSuffix(record.OriginalName, filepath.Ext(record.OriginalName)) + ".pdf"
-		files[pdfFilename] = record.PDFData
+// streamZip writes a ZIP archive straight to w, fetching and writing one
+// file at a time instead of buffering every PDF in memory first. Names are
+// resolved before any header is written so a request where every ID is
+// invalid gets a 404 instead of a 200 with an empty archive.
+func (h *Handler) streamZip(w http.ResponseWriter, ids []int64) {
+	type entry struct {
+		id           int64
+		originalName string
 	}
 
-	// Create ZIP
-	zipData, err := CreateZip(files)
-	if err != nil {
-		http.Error(w, "Error creating ZIP", http.StatusInternalServerError)
-		log.Printf("Error creating ZIP: %v", err)
+	var entries []entry
+	for _, id := range ids {
+		originalName, err := h.db.GetFileName(id)
+		if err != nil {
+			log.Printf("Error fetching file name for ID %d: %v", id, err)
+			continue
+		}
+		entries = append(entries, entry{id: id, originalName: originalName})
+	}
+
+	if len(entries) == 0 {
+		http.Error(w, "No files found", http.StatusNotFound)
 		return
 	}
 
-	// Set headers for ZIP download
 	w.Header().Set("Content-Type", "application/zip")
 	w.Header().Set("Content-Disposition", "attachment; filename=\"converted_pdfs.zip\"")
-	w.Header().Set("Content-Length", strconv.Itoa(len(zipData)))
 
-	// Write ZIP data
-	_, err = w.Write(zipData)
-	if err != nil {
-		log.Printf("Error writing ZIP data: %v", err)
+	zipWriter := zip.NewWriter(w)
+
+	for _, e := range entries {
+		pdfFilename := strings.TrimSuffix(e.originalName, filepath.Ext(e.originalName)) + ".pdf"
+		entryWriter, err := zipWriter.Create(pdfFilename)
+		if err != nil {
+			log.Printf("Error creating zip entry for file ID %d: %v", e.id, err)
+			continue
+		}
+
+		if err := h.db.StreamFile(e.id, entryWriter); err != nil {
+			log.Printf("Error streaming file ID %d into zip: %v", e.id, err)
+			continue
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		log.Printf("Error closing zip writer: %v", err)
+	}
+}
+
+// streamTarGz writes a gzip-compressed tar archive straight to w, fetching
+// and writing one file at a time. Names are resolved before any header is
+// written so a request where every ID is invalid gets a 404 instead of a
+// 200 with an empty archive.
+func (h *Handler) streamTarGz(w http.ResponseWriter, ids []int64) {
+	// Unlike zip, tar requires the size up front in the header, so each
+	// entry needs its full record fetched rather than streamed
+	// incrementally; records are fetched one at a time as they are written.
+	var found []int64
+	for _, id := range ids {
+		if _, err := h.db.GetFileName(id); err != nil {
+			log.Printf("Error fetching file name for ID %d: %v", id, err)
+			continue
+		}
+		found = append(found, id)
+	}
+
+	if len(found) == 0 {
+		http.Error(w, "No files found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"converted_pdfs.tar.gz\"")
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, id := range found {
+		record, err := h.db.GetFile(id)
+		if err != nil {
+			log.Printf("Error fetching file ID %d: %v", id, err)
+			continue
+		}
+
+		pdfFilename := strings.TrimSuffix(record.OriginalName, filepath.Ext(record.OriginalName)) + ".pdf"
+
+		header := &tar.Header{
+			Name:    pdfFilename,
+			Size:    int64(len(record.PDFData)),
+			Mode:    0644,
+			ModTime: record.UploadedAt,
+		}
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			log.Printf("Error writing tar header for file ID %d: %v", record.ID, err)
+			continue
+		}
+
+		if _, err := tarWriter.Write(record.PDFData); err != nil {
+			log.Printf("Error writing file ID %d into tar: %v", record.ID, err)
+			continue
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		log.Printf("Error closing tar writer: %v", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		log.Printf("Error closing gzip writer: %v", err)
 	}
 }
 
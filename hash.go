@@ -0,0 +1,13 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// sha256Hex returns the lowercase hex-encoded SHA-256 digest of data, used
+// as the content address for source files and generated PDFs.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
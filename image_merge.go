@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// NamedBlob pairs an uploaded file's name with its raw bytes, used wherever
+// multiple source files need to be processed together (e.g. image merging).
+type NamedBlob struct {
+	Filename string
+	Data     []byte
+}
+
+// MergeLayout controls how multiple images are arranged across the pages
+// of a merged PDF.
+type MergeLayout string
+
+const (
+	// MergeLayoutFit gives each image its own page sized to match its
+	// aspect ratio, same as converting each image individually.
+	MergeLayoutFit MergeLayout = "fit"
+	// MergeLayoutGrid tiles multiple images per page in a 2x2 grid.
+	MergeLayoutGrid MergeLayout = "grid"
+	// MergeLayoutOnePerPage places each image on its own standard-size
+	// page, scaled to fit within the page margins.
+	MergeLayoutOnePerPage MergeLayout = "one-per-page"
+)
+
+// PageSize is the target page size for layouts that use a fixed page.
+type PageSize string
+
+const (
+	PageSizeA4     PageSize = "A4"
+	PageSizeLetter PageSize = "Letter"
+	PageSizeAuto   PageSize = "auto"
+)
+
+// MergeOptions configures ConvertImagesToPDF.
+type MergeOptions struct {
+	Layout   MergeLayout
+	PageSize PageSize
+}
+
+// isImageFilename reports whether name has one of the extensions handled
+// by the image conversion strategy.
+func isImageFilename(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	default:
+		return false
+	}
+}
+
+const gridCols = 2
+const gridRows = 2
+const gridMarginMM = 10.0
+const pageMarginMM = 10.0
+
+// ConvertImagesToPDF merges multiple image files into a single PDF
+// according to opts, producing one combined document instead of one PDF
+// per file.
+func ConvertImagesToPDF(files []NamedBlob, opts MergeOptions) ([]byte, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no images to merge")
+	}
+
+	layout := opts.Layout
+	if layout == "" {
+		layout = MergeLayoutFit
+	}
+
+	switch layout {
+	case MergeLayoutFit:
+		return mergeImagesFit(files)
+	case MergeLayoutOnePerPage:
+		return mergeImagesOnePerPage(files, opts.PageSize)
+	case MergeLayoutGrid:
+		return mergeImagesGrid(files, opts.PageSize)
+	default:
+		return nil, fmt.Errorf("unsupported merge layout: %s", layout)
+	}
+}
+
+// mergeImagesFit gives each image its own page custom-sized to its aspect
+// ratio, reusing the same sizing rules as convertImageToPDF.
+func mergeImagesFit(files []NamedBlob) ([]byte, error) {
+	var pdf *gofpdf.Fpdf
+
+	for i, f := range files {
+		widthMM, heightMM, err := fitPageDims(f.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to size image %s: %w", f.Filename, err)
+		}
+
+		if i == 0 {
+			pdf = gofpdf.NewCustom(&gofpdf.InitType{
+				OrientationStr: "P",
+				UnitStr:        "mm",
+				Size:           gofpdf.SizeType{Wd: widthMM, Ht: heightMM},
+			})
+		} else {
+			pdf.AddPageFormat("P", gofpdf.SizeType{Wd: widthMM, Ht: heightMM})
+		}
+
+		if i == 0 {
+			pdf.AddPage()
+		}
+
+		if err := placeImage(pdf, f, i, 0, 0, widthMM, heightMM); err != nil {
+			return nil, err
+		}
+	}
+
+	return outputPDF(pdf)
+}
+
+// mergeImagesOnePerPage places each image on its own standard-size page,
+// scaled to fit within the margins while preserving aspect ratio.
+func mergeImagesOnePerPage(files []NamedBlob, pageSize PageSize) ([]byte, error) {
+	pageWidth, pageHeight := standardPageDims(pageSize)
+
+	pdf := gofpdf.New("P", "mm", string(resolveStandardSize(pageSize)), "")
+
+	for i, f := range files {
+		pdf.AddPage()
+
+		x, y, w, h, err := fitWithinBounds(f.Data, pageWidth-2*pageMarginMM, pageHeight-2*pageMarginMM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to size image %s: %w", f.Filename, err)
+		}
+
+		if err := placeImage(pdf, f, i, pageMarginMM+x, pageMarginMM+y, w, h); err != nil {
+			return nil, err
+		}
+	}
+
+	return outputPDF(pdf)
+}
+
+// mergeImagesGrid tiles images across a fixed grid of cells per page.
+func mergeImagesGrid(files []NamedBlob, pageSize PageSize) ([]byte, error) {
+	pageWidth, pageHeight := standardPageDims(pageSize)
+
+	cellWidth := (pageWidth - gridMarginMM*(gridCols+1)) / gridCols
+	cellHeight := (pageHeight - gridMarginMM*(gridRows+1)) / gridRows
+	perPage := gridCols * gridRows
+
+	pdf := gofpdf.New("P", "mm", string(resolveStandardSize(pageSize)), "")
+
+	for i, f := range files {
+		if i%perPage == 0 {
+			pdf.AddPage()
+		}
+
+		cell := i % perPage
+		col := cell % gridCols
+		row := cell / gridCols
+
+		cellX := gridMarginMM + float64(col)*(cellWidth+gridMarginMM)
+		cellY := gridMarginMM + float64(row)*(cellHeight+gridMarginMM)
+
+		x, y, w, h, err := fitWithinCell(f.Data, cellWidth, cellHeight)
+		if err != nil {
+			return nil, fmt.Errorf("failed to size image %s: %w", f.Filename, err)
+		}
+
+		if err := placeImage(pdf, f, i, cellX+x, cellY+y, w, h); err != nil {
+			return nil, err
+		}
+	}
+
+	return outputPDF(pdf)
+}
+
+// fitPageDims computes a page size matching the image's aspect ratio. If
+// either side exceeds A4, a landscape image is scaled to A4's width and any
+// other image to A4's height, so a near-square image can still produce a
+// page wider than A4. This mirrors the sizing logic in convertImageToPDF.
+func fitPageDims(data []byte) (widthMM, heightMM float64, err error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	width := float64(bounds.Dx())
+	height := float64(bounds.Dy())
+
+	const dpi = 72.0
+	const mmPerInch = 25.4
+	widthMM = (width / dpi) * mmPerInch
+	heightMM = (height / dpi) * mmPerInch
+
+	maxWidth := 210.0
+	maxHeight := 297.0
+
+	if widthMM > maxWidth || heightMM > maxHeight {
+		ratio := width / height
+		if widthMM > heightMM {
+			widthMM = maxWidth
+			heightMM = widthMM / ratio
+		} else {
+			heightMM = maxHeight
+			widthMM = heightMM * ratio
+		}
+	}
+
+	return widthMM, heightMM, nil
+}
+
+// fitWithinBounds scales an image to fit within a bounding box while
+// preserving aspect ratio, returning the top-left position and size
+// centered within the box.
+func fitWithinBounds(data []byte, boundsWidth, boundsHeight float64) (x, y, w, h float64, err error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	width := float64(bounds.Dx())
+	height := float64(bounds.Dy())
+	ratio := width / height
+
+	w = boundsWidth
+	h = w / ratio
+	if h > boundsHeight {
+		h = boundsHeight
+		w = h * ratio
+	}
+
+	x = (boundsWidth - w) / 2
+	y = (boundsHeight - h) / 2
+
+	return x, y, w, h, nil
+}
+
+// fitWithinCell is fitWithinBounds without the surrounding page margin
+// offset, used for grid cells.
+func fitWithinCell(data []byte, cellWidth, cellHeight float64) (x, y, w, h float64, err error) {
+	return fitWithinBounds(data, cellWidth, cellHeight)
+}
+
+// placeImage registers and draws a single image onto the current page.
+// index must be unique across every placeImage call for a given pdf (e.g.
+// the file's position in the batch): gofpdf caches image registrations by
+// name and silently reuses the first match, so two uploads sharing a
+// filename would otherwise collapse into one duplicated image.
+func placeImage(pdf *gofpdf.Fpdf, f NamedBlob, index int, x, y, w, h float64) error {
+	ext := strings.ToLower(filepath.Ext(f.Filename))
+	imageType := "jpg"
+	if ext == ".png" {
+		imageType = "png"
+	}
+
+	imageName := fmt.Sprintf("%s#%d", f.Filename, index)
+
+	imageReader := bytes.NewReader(f.Data)
+	pdf.RegisterImageOptionsReader(imageName, gofpdf.ImageOptions{ImageType: imageType}, imageReader)
+	if pdf.Error() != nil {
+		return fmt.Errorf("failed to register image %s: %w", f.Filename, pdf.Error())
+	}
+
+	pdf.ImageOptions(imageName, x, y, w, h, false, gofpdf.ImageOptions{ImageType: imageType}, 0, "")
+	if pdf.Error() != nil {
+		return fmt.Errorf("failed to add image %s to PDF: %w", f.Filename, pdf.Error())
+	}
+
+	return nil
+}
+
+func outputPDF(pdf *gofpdf.Fpdf) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to output PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// resolveStandardSize maps PageSize to a gofpdf standard size name,
+// defaulting to A4 for an unrecognized or "auto" value.
+func resolveStandardSize(pageSize PageSize) PageSize {
+	if pageSize == PageSizeLetter {
+		return PageSizeLetter
+	}
+	return PageSizeA4
+}
+
+// standardPageDims returns the page dimensions in mm for a PageSize.
+func standardPageDims(pageSize PageSize) (widthMM, heightMM float64) {
+	if pageSize == PageSizeLetter {
+		return 215.9, 279.4
+	}
+	return 210.0, 297.0
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+)
+
+// maxJobRetries bounds how many times a failed conversion is retried before
+// the job is marked failed for good.
+const maxJobRetries = 2
+
+// conversionTask is the in-memory payload for a persisted job: the job row
+// tracks state, but the uploaded bytes live only in the queue. convert
+// performs the actual conversion, letting the same worker pool handle both
+// single-file conversions and multi-file merges.
+type conversionTask struct {
+	jobID      int64
+	filename   string
+	sourceHash string // empty when the task has no single content-addressable source (merge/OCR batches)
+	convert    func() ([]byte, error)
+}
+
+// JobQueue is a pool of goroutine workers that pick up conversion tasks and
+// run each task's convert function, persisting progress on the associated job
+// row as they go.
+type JobQueue struct {
+	db          *Database
+	tasks       chan conversionTask
+	workerCount int
+	wg          sync.WaitGroup
+
+	// mu guards closed and serializes every send on tasks against Shutdown
+	// closing it, so a retry from fail() can never race close(tasks).
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewJobQueue creates a queue backed by workerCount goroutines. Call Start
+// to launch the workers and Shutdown to drain in-flight tasks.
+func NewJobQueue(db *Database, workerCount int) *JobQueue {
+	if workerCount < 1 {
+		workerCount = 1
+	}
+
+	return &JobQueue{
+		db:          db,
+		tasks:       make(chan conversionTask, workerCount*4),
+		workerCount: workerCount,
+	}
+}
+
+// Start launches the worker goroutines. It must be called once before any
+// task is enqueued.
+func (q *JobQueue) Start() {
+	for i := 1; i <= q.workerCount; i++ {
+		q.wg.Add(1)
+		go q.worker(i)
+	}
+}
+
+// Enqueue submits a single-file conversion task for the given job. It
+// blocks if every worker is busy and the internal buffer is full, applying
+// natural backpressure to HandleUpload. The source bytes are hashed so the
+// worker can short-circuit conversion if the same file was already
+// uploaded.
+func (q *JobQueue) Enqueue(jobID int64, filename string, data []byte) {
+	q.enqueue(conversionTask{
+		jobID:      jobID,
+		filename:   filename,
+		sourceHash: sha256Hex(data),
+		convert:    func() ([]byte, error) { return ConvertToPDF(filename, data) },
+	})
+}
+
+// EnqueueMerge submits a multi-image merge task for the given job, saving
+// the combined PDF as a single row once conversion succeeds.
+func (q *JobQueue) EnqueueMerge(jobID int64, resultName string, files []NamedBlob, opts MergeOptions) {
+	q.enqueue(conversionTask{
+		jobID:    jobID,
+		filename: resultName,
+		convert:  func() ([]byte, error) { return ConvertImagesToPDF(files, opts) },
+	})
+}
+
+// EnqueueOCR submits an OCR conversion task for one or more images, falling
+// back to the plain image conversion path if tesseract isn't installed.
+func (q *JobQueue) EnqueueOCR(jobID int64, resultName string, files []NamedBlob, opts OCROptions) {
+	ocrConvert := func() ([]byte, error) { return ConvertImagesToPDFWithOCR(files, opts) }
+	fallback := func() ([]byte, error) { return ConvertImagesToPDF(files, MergeOptions{}) }
+
+	q.enqueue(conversionTask{
+		jobID:    jobID,
+		filename: resultName,
+		convert:  withOCRFallback(ocrConvert, fallback),
+	})
+}
+
+// enqueue submits task for processing, reporting false instead of sending if
+// Shutdown has already closed the queue. Holding mu for the duration of the
+// send serializes every enqueue against Shutdown's close(q.tasks), so a
+// retry from fail() can never land on a channel that's mid-close.
+func (q *JobQueue) enqueue(task conversionTask) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return false
+	}
+	q.tasks <- task
+	return true
+}
+
+// retryEnqueue resubmits a failed task using a non-blocking send. fail()
+// runs inside a worker's own `for task := range q.tasks` loop, so a blocking
+// send here could leave every worker stuck trying to re-submit with no
+// goroutine left to drain the channel, deadlocking the queue (and, behind
+// it, Shutdown and new /upload requests). If the buffer is full the job
+// can't be retried right now, so the caller should mark it failed instead.
+func (q *JobQueue) retryEnqueue(task conversionTask) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return false
+	}
+
+	select {
+	case q.tasks <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown closes the task channel and waits for in-flight jobs to finish,
+// allowing callers to drain work before the process exits.
+func (q *JobQueue) Shutdown() {
+	q.mu.Lock()
+	q.closed = true
+	close(q.tasks)
+	q.mu.Unlock()
+
+	q.wg.Wait()
+}
+
+func (q *JobQueue) worker(workerID int) {
+	defer q.wg.Done()
+
+	for task := range q.tasks {
+		q.process(workerID, task)
+	}
+}
+
+func (q *JobQueue) process(workerID int, task conversionTask) {
+	if err := q.db.MarkJobRunning(task.jobID, workerID); err != nil {
+		log.Printf("worker %d: failed to mark job %d running: %v", workerID, task.jobID, err)
+	}
+
+	if task.sourceHash != "" {
+		existing, err := q.db.GetFileBySourceHash(task.sourceHash)
+		if err == nil {
+			log.Printf("worker %d: job %d matches existing file %d by source hash, skipping conversion", workerID, task.jobID, existing.ID)
+			if err := q.db.MarkJobDone(task.jobID, int64(existing.ID)); err != nil {
+				log.Printf("worker %d: failed to mark job %d done: %v", workerID, task.jobID, err)
+			}
+			return
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("worker %d: failed to check source hash for job %d: %v", workerID, task.jobID, err)
+		}
+	}
+
+	pdfData, err := task.convert()
+	if err != nil {
+		q.fail(workerID, task, err)
+		return
+	}
+
+	fileID, err := q.db.SaveFile(task.filename, pdfData, task.sourceHash, sha256Hex(pdfData))
+	if err != nil {
+		q.fail(workerID, task, err)
+		return
+	}
+
+	if err := q.db.MarkJobDone(task.jobID, fileID); err != nil {
+		log.Printf("worker %d: failed to mark job %d done: %v", workerID, task.jobID, err)
+	}
+}
+
+func (q *JobQueue) fail(workerID int, task conversionTask, convErr error) {
+	job, err := q.db.GetJob(task.jobID)
+	if err != nil {
+		log.Printf("worker %d: failed to load job %d after error: %v", workerID, task.jobID, err)
+		return
+	}
+
+	retryCount := job.RetryCount + 1
+	if retryCount <= maxJobRetries {
+		log.Printf("worker %d: job %d failed, retrying (retry %d/%d): %v", workerID, task.jobID, retryCount, maxJobRetries, convErr)
+		if err := q.db.IncrementJobRetry(task.jobID, retryCount, convErr.Error()); err != nil {
+			log.Printf("worker %d: failed to record retry for job %d: %v", workerID, task.jobID, err)
+		}
+		if !q.retryEnqueue(task) {
+			log.Printf("worker %d: job %d not retried, queue is shutting down or full", workerID, task.jobID)
+			if err := q.db.MarkJobFailed(task.jobID, retryCount, convErr.Error()); err != nil {
+				log.Printf("worker %d: failed to mark job %d failed: %v", workerID, task.jobID, err)
+			}
+		}
+		return
+	}
+
+	log.Printf("worker %d: job %d failed permanently: %v", workerID, task.jobID, convErr)
+	if err := q.db.MarkJobFailed(task.jobID, retryCount, convErr.Error()); err != nil {
+		log.Printf("worker %d: failed to mark job %d failed: %v", workerID, task.jobID, err)
+	}
+}
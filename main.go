@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 )
 
+// defaultWorkerCount is used when WORKER_COUNT is unset or invalid.
+const defaultWorkerCount = 4
+
 func main() {
 	// Get database configuration from environment variables
 	dbHost := os.Getenv("DB_HOST")
@@ -52,24 +57,65 @@ func main() {
 
 	log.Println("Database initialized successfully")
 
+	// Worker count for the conversion job queue, configurable since
+	// LibreOffice docx conversions are slow and benefit from more workers
+	// than default.
+	workerCount := defaultWorkerCount
+	if raw := os.Getenv("WORKER_COUNT"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			workerCount = n
+		} else {
+			log.Printf("Invalid WORKER_COUNT %q, using default of %d", raw, defaultWorkerCount)
+		}
+	}
+
+	queue := NewJobQueue(db, workerCount)
+	queue.Start()
+	log.Printf("Job queue started with %d workers", workerCount)
+
+	uploads, err := NewChunkUploadManager()
+	if err != nil {
+		log.Fatalf("Failed to initialize chunked upload manager: %v", err)
+	}
+
 	// Create handler
-	handler := NewHandler(db)
+	handler := NewHandler(db, queue, uploads)
+
+	// OPDS routes are gated behind Basic Auth so the converted-PDF library
+	// isn't world-readable; configure via OPDS_USER/OPDS_PASSWORD.
+	opdsUser := os.Getenv("OPDS_USER")
+	opdsPassword := os.Getenv("OPDS_PASSWORD")
+	if opdsUser == "" && opdsPassword == "" {
+		log.Println("OPDS_USER/OPDS_PASSWORD not set, OPDS feed is unauthenticated")
+	}
 
 	// Setup routes
-	http.HandleFunc("/", serveHome)
-	http.HandleFunc("/upload", handler.HandleUpload)
-	http.HandleFunc("/download", handler.HandleDownload)
-	http.HandleFunc("/download-zip", handler.HandleDownloadZip)
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", serveHome)
+	mux.HandleFunc("/upload", handler.HandleUpload)
+	mux.HandleFunc("/upload/init", handler.HandleUploadInit)
+	mux.HandleFunc("/upload/chunk", handler.HandleUploadChunk)
+	mux.HandleFunc("/upload/complete", handler.HandleUploadComplete)
+	// /download serves the same files the OPDS catalog links to, so it must
+	// sit behind the same Basic Auth check or the catalog's gate is
+	// pointless: anyone could pull files directly by guessing/iterating IDs.
+	mux.HandleFunc("/download", basicAuthMiddleware(opdsUser, opdsPassword, handler.HandleDownload))
+	mux.HandleFunc("/download-zip", basicAuthMiddleware(opdsUser, opdsPassword, handler.HandleDownloadZip))
+	mux.HandleFunc("/jobs", handler.HandleJobsStatus)
+	mux.HandleFunc("/jobs/", handler.HandleJobStatus)
+	mux.HandleFunc("/api/opds", basicAuthMiddleware(opdsUser, opdsPassword, handler.HandleOPDSCatalog))
+	mux.HandleFunc("/api/opds/", basicAuthMiddleware(opdsUser, opdsPassword, handler.HandleOPDSEntry))
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
 
 	// Start server
 	port := ":19080"
+	srv := &http.Server{Addr: port, Handler: mux}
+
 	log.Printf("Server starting on http://localhost%s", port)
 	log.Println("Supported formats: JPG, JPEG, PNG, TXT")
 
-	// Setup graceful shutdown
 	go func() {
-		if err := http.ListenAndServe(port, nil); err != nil {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
@@ -80,6 +126,18 @@ func main() {
 	<-sigChan
 
 	log.Println("Shutting down server...")
+
+	// Stop accepting new HTTP requests, then drain in-flight conversion jobs
+	// before the process exits.
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
+
+	log.Println("Draining in-flight conversion jobs...")
+	queue.Shutdown()
+	log.Println("Shutdown complete")
 }
 
 func serveHome(w http.ResponseWriter, r *http.Request) {
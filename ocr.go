@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrTesseractNotFound is returned when the tesseract binary cannot be
+// located in PATH. Callers should fall back to the non-OCR conversion path.
+var ErrTesseractNotFound = errors.New("tesseract binary not found in PATH")
+
+// ErrPdfuniteNotFound is returned when OCRing more than one image requires
+// merging per-image PDFs but the pdfunite binary cannot be located in PATH.
+// Callers should fall back to the non-OCR conversion path, same as
+// ErrTesseractNotFound.
+var ErrPdfuniteNotFound = errors.New("pdfunite binary not found in PATH")
+
+// OCROptions configures OCR text-layer generation for image uploads.
+type OCROptions struct {
+	Lang string
+}
+
+const defaultOCRLang = "eng"
+
+func tesseractAvailable() bool {
+	_, err := exec.LookPath("tesseract")
+	return err == nil
+}
+
+func pdfuniteAvailable() bool {
+	_, err := exec.LookPath("pdfunite")
+	return err == nil
+}
+
+// ConvertImageToPDFWithOCR runs tesseract on a single image, producing a
+// searchable PDF with an invisible text layer natively (tesseract's "pdf"
+// output format), instead of the plain image-on-page PDF convertImageToPDF
+// produces.
+func ConvertImageToPDFWithOCR(filename string, data []byte, opts OCROptions) ([]byte, error) {
+	if !tesseractAvailable() {
+		return nil, ErrTesseractNotFound
+	}
+
+	lang := opts.Lang
+	if lang == "" {
+		lang = defaultOCRLang
+	}
+
+	tmpDir, err := os.MkdirTemp("", "ocr-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	imgPath := filepath.Join(tmpDir, filepath.Base(filename))
+	if err := os.WriteFile(imgPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write temp image: %w", err)
+	}
+
+	outBase := strings.TrimSuffix(imgPath, filepath.Ext(imgPath))
+
+	// tesseract's native "pdf" output format embeds an invisible text
+	// layer over the original image, producing a searchable PDF.
+	cmd := exec.Command("tesseract", imgPath, outBase, "-l", lang, "pdf")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract OCR failed: %v, stderr: %s", err, stderr.String())
+	}
+
+	pdfData, err := os.ReadFile(outBase + ".pdf")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCR'd PDF: %w", err)
+	}
+
+	return pdfData, nil
+}
+
+// ConvertImagesToPDFWithOCR OCRs each image individually, then merges the
+// resulting per-image PDFs into a single searchable document when more
+// than one image is uploaded.
+func ConvertImagesToPDFWithOCR(files []NamedBlob, opts OCROptions) ([]byte, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no images to OCR")
+	}
+
+	if len(files) == 1 {
+		return ConvertImageToPDFWithOCR(files[0].Filename, files[0].Data, opts)
+	}
+
+	if !tesseractAvailable() {
+		return nil, ErrTesseractNotFound
+	}
+	if !pdfuniteAvailable() {
+		return nil, ErrPdfuniteNotFound
+	}
+
+	tmpDir, err := os.MkdirTemp("", "ocr-merge-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var pdfPaths []string
+	for i, f := range files {
+		pdfData, err := ConvertImageToPDFWithOCR(f.Filename, f.Data, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to OCR %s: %w", f.Filename, err)
+		}
+
+		pdfPath := filepath.Join(tmpDir, fmt.Sprintf("page-%d.pdf", i))
+		if err := os.WriteFile(pdfPath, pdfData, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write OCR page for %s: %w", f.Filename, err)
+		}
+		pdfPaths = append(pdfPaths, pdfPath)
+	}
+
+	mergedPath := filepath.Join(tmpDir, "merged.pdf")
+	args := append(append([]string{}, pdfPaths...), mergedPath)
+	cmd := exec.Command("pdfunite", args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("pdfunite failed to merge OCR pages: %v, stderr: %s", err, stderr.String())
+	}
+
+	mergedData, err := os.ReadFile(mergedPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read merged OCR PDF: %w", err)
+	}
+
+	return mergedData, nil
+}
+
+// withOCRFallback wraps an OCR conversion so that a missing tesseract or
+// pdfunite binary falls back to the non-OCR path rather than failing the
+// job.
+func withOCRFallback(ocrConvert func() ([]byte, error), fallback func() ([]byte, error)) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		data, err := ocrConvert()
+		if errors.Is(err, ErrTesseractNotFound) || errors.Is(err, ErrPdfuniteNotFound) {
+			log.Printf("%v, falling back to non-OCR conversion", err)
+			return fallback()
+		}
+		return data, err
+	}
+}
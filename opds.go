@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// opdsCatalogPageSize bounds how many entries /api/opds returns per page.
+const opdsCatalogPageSize = 50
+
+// OPDS 1.2 (Atom-based) feed structures. Only the elements e-reader apps
+// such as KOReader and Moon+ Reader actually read are included.
+type opdsFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []opdsLink  `xml:"link"`
+	Entries []opdsEntry `xml:"entry"`
+}
+
+type opdsLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type opdsEntry struct {
+	ID      string     `xml:"id"`
+	Title   string     `xml:"title"`
+	Updated string     `xml:"updated"`
+	Links   []opdsLink `xml:"link"`
+}
+
+func opdsAcquisitionLink(fileID int) opdsLink {
+	return opdsLink{
+		Rel:  "http://opds-spec.org/acquisition",
+		Href: fmt.Sprintf("/download?id=%d", fileID),
+		Type: "application/pdf",
+	}
+}
+
+func opdsEntryFromRecord(record *FileRecord) opdsEntry {
+	return opdsEntry{
+		ID:      fmt.Sprintf("urn:pdf-converter:file:%d", record.ID),
+		Title:   record.OriginalName,
+		Updated: record.UploadedAt.UTC().Format(time.RFC3339),
+		Links:   []opdsLink{opdsAcquisitionLink(record.ID)},
+	}
+}
+
+// HandleOPDSCatalog handles GET /api/opds, returning an OPDS acquisition
+// feed of converted PDFs, newest first, so e-reader apps can browse the
+// library. Each page holds at most opdsCatalogPageSize entries and links to
+// the next page via ?offset= when it is full.
+func (h *Handler) HandleOPDSCatalog(w http.ResponseWriter, r *http.Request) {
+	offset := 0
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 {
+			offset = n
+		}
+	}
+
+	records, err := h.db.ListFiles(opdsCatalogPageSize, offset)
+	if err != nil {
+		log.Printf("Error listing files for OPDS catalog: %v", err)
+		http.Error(w, "Error retrieving catalog", http.StatusInternalServerError)
+		return
+	}
+
+	feed := opdsFeed{
+		ID:      "urn:pdf-converter:catalog",
+		Title:   "Converted PDFs",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Links: []opdsLink{
+			{Rel: "self", Href: "/api/opds", Type: "application/atom+xml;profile=opds-catalog;kind=acquisition"},
+		},
+	}
+
+	if len(records) == opdsCatalogPageSize {
+		feed.Links = append(feed.Links, opdsLink{
+			Rel:  "next",
+			Href: fmt.Sprintf("/api/opds?offset=%d", offset+opdsCatalogPageSize),
+			Type: "application/atom+xml;profile=opds-catalog;kind=acquisition",
+		})
+	}
+
+	for _, record := range records {
+		feed.Entries = append(feed.Entries, opdsEntryFromRecord(record))
+	}
+
+	writeOPDSFeed(w, feed)
+}
+
+// HandleOPDSEntry handles GET /api/opds/{id}, returning a single-entry OPDS
+// feed for one converted PDF.
+func (h *Handler) HandleOPDSEntry(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/opds/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid file ID", http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.db.GetFileMeta(id)
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	feed := opdsFeed{
+		ID:      fmt.Sprintf("urn:pdf-converter:file:%d", record.ID),
+		Title:   record.OriginalName,
+		Updated: record.UploadedAt.UTC().Format(time.RFC3339),
+		Links: []opdsLink{
+			{Rel: "self", Href: fmt.Sprintf("/api/opds/%d", record.ID), Type: "application/atom+xml;type=entry"},
+		},
+		Entries: []opdsEntry{opdsEntryFromRecord(record)},
+	}
+
+	writeOPDSFeed(w, feed)
+}
+
+func writeOPDSFeed(w http.ResponseWriter, feed opdsFeed) {
+	w.Header().Set("Content-Type", "application/atom+xml;profile=opds-catalog;kind=acquisition")
+	w.Write([]byte(xml.Header))
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		log.Printf("Error encoding OPDS feed: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ConversionStrategy converts a single source file into PDF bytes.
+// Extensions are matched case-insensitively, including the leading dot
+// (e.g. ".png").
+type ConversionStrategy interface {
+	Extensions() []string
+	Convert(filename string, data []byte) ([]byte, error)
+}
+
+var strategyRegistry = make(map[string]ConversionStrategy)
+
+// RegisterStrategy wires a ConversionStrategy into ConvertToPDF for each of
+// the extensions it declares. Strategies register themselves via init(),
+// the way Go's database/sql drivers do; unlike sql.Register, a later
+// registration for the same extension replaces the earlier one.
+func RegisterStrategy(s ConversionStrategy) {
+	for _, ext := range s.Extensions() {
+		strategyRegistry[strings.ToLower(ext)] = s
+	}
+}
+
+func init() {
+	RegisterStrategy(imageStrategy{})
+	RegisterStrategy(textStrategy{})
+	RegisterStrategy(docxStrategy{})
+}
+
+// ConvertToPDF converts various file types to PDF by dispatching to the
+// ConversionStrategy registered for the file's extension.
+func ConvertToPDF(filename string, data []byte) ([]byte, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	strategy, ok := strategyRegistry[ext]
+	if !ok {
+		return nil, fmt.Errorf("unsupported file type: %s", ext)
+	}
+
+	return strategy.Convert(filename, data)
+}
+
+type imageStrategy struct{}
+
+func (imageStrategy) Extensions() []string { return []string{".jpg", ".jpeg", ".png"} }
+
+func (imageStrategy) Convert(filename string, data []byte) ([]byte, error) {
+	return convertImageToPDF(filename, data)
+}
+
+type textStrategy struct{}
+
+func (textStrategy) Extensions() []string { return []string{".txt"} }
+
+func (textStrategy) Convert(filename string, data []byte) ([]byte, error) {
+	return convertTextToPDF(filename, data)
+}
+
+type docxStrategy struct{}
+
+func (docxStrategy) Extensions() []string { return []string{".docx"} }
+
+func (docxStrategy) Convert(filename string, data []byte) ([]byte, error) {
+	return convertDocxToPDF(filename, data)
+}